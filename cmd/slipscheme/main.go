@@ -0,0 +1,105 @@
+// Command slipscheme generates Go struct definitions from JSON Schema
+// files. See the slipscheme package for the library API this wraps.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/zhongjie-cai/slipscheme"
+)
+
+func main() {
+	outputDir := flag.String("dir", "tmp", "output directory for go files.")
+	pkgName := flag.String("pkg", "model", "package namespace for go files")
+	overwrite := flag.Bool("overwrite", true, "force overwriting existing go files")
+	stdout := flag.Bool("stdout", false, "print go code to stdout rather than files")
+	format := flag.Bool("fmt", true, "pass code through gofmt")
+	comments := flag.Bool("comments", true, "enable/disable print comments")
+	baseDir := flag.String("base-dir", "", "base directory for resolving relative $ref targets not in the file list")
+	offline := flag.Bool("offline", false, "refuse to fetch http(s) $ref targets")
+	validate := flag.Bool("validate", false, "emit a Validate() method enforcing schema constraints alongside each struct")
+	nameOverrides := flag.String("name-overrides", "", "path to a JSON file mapping schema pointer path (e.g. /properties/user) to the Go type name to use for it")
+	input := flag.String("input", "", "input document format: jsonschema, openapi or asyncapi (default: detect per file)")
+	uuidType := flag.String("uuid-type", "", "Go type generated for string schemas with format: uuid (default: string)")
+
+	flag.Parse()
+
+	if _, err := os.Stat(*outputDir); os.IsNotExist(err) {
+		os.MkdirAll(*outputDir, 0755)
+	}
+
+	overrides, err := loadNameOverrides(*nameOverrides)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	inputFormat, err := parseInputFormat(*input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	processor := slipscheme.NewProcessor(slipscheme.Options{
+		OutputDir:     *outputDir,
+		PackageName:   *pkgName,
+		Overwrite:     *overwrite,
+		Stdout:        *stdout,
+		Fmt:           *format,
+		Comment:       *comments,
+		BaseDir:       *baseDir,
+		OfflineMode:   *offline,
+		Validate:      *validate,
+		NameOverrides: overrides,
+		InputFormat:   inputFormat,
+		UUIDType:      *uuidType,
+	})
+
+	args := flag.Args()
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: %s <schema file> [<schema file> ...]\n", os.Args[0])
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	if err := processor.Load(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	if err := processor.Process(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// loadNameOverrides reads path as a JSON object mapping schema pointer path
+// to Go type name. An empty path is not an error; it just means no
+// overrides were requested.
+func loadNameOverrides(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading name overrides file: %w", err)
+	}
+	overrides := map[string]string{}
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("parsing name overrides file: %w", err)
+	}
+	return overrides, nil
+}
+
+// parseInputFormat validates the -input flag value, leaving it empty (auto
+// detection) when the flag wasn't given.
+func parseInputFormat(value string) (slipscheme.InputFormat, error) {
+	switch slipscheme.InputFormat(value) {
+	case slipscheme.InputAuto, slipscheme.InputJSONSchema, slipscheme.InputOpenAPI, slipscheme.InputAsyncAPI:
+		return slipscheme.InputFormat(value), nil
+	}
+	return "", fmt.Errorf("invalid -input %q: must be jsonschema, openapi or asyncapi", value)
+}
@@ -0,0 +1,60 @@
+package slipscheme
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRefToNamedComponentReusesType covers a $ref from one OpenAPI component
+// to another (e.g. Pet.owner -> #/components/schemas/Owner): the property
+// should be typed as a pointer to the existing Owner type rather than
+// generating a duplicate, differently-named struct of the same shape.
+func TestRefToNamedComponentReusesType(t *testing.T) {
+	dir := t.TempDir()
+	specPath := filepath.Join(dir, "petstore.json")
+	if err := os.WriteFile(specPath, []byte(`{
+		"openapi": "3.0.0",
+		"info": {"title": "Petstore", "version": "1.0.0"},
+		"paths": {},
+		"components": {
+			"schemas": {
+				"Owner": {
+					"type": "object",
+					"properties": {"name": {"type": "string"}}
+				},
+				"Pet": {
+					"type": "object",
+					"properties": {
+						"name": {"type": "string"},
+						"owner": {"$ref": "#/components/schemas/Owner"}
+					}
+				}
+			}
+		}
+	}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	emitter := &MemoryEmitter{}
+	processor := NewProcessor(Options{PackageName: "model", Emitter: emitter})
+	if err := processor.Load([]string{specPath}); err != nil {
+		t.Fatal(err)
+	}
+	if err := processor.Process(context.Background()); err != nil {
+		t.Fatalf("Process: %s", err)
+	}
+
+	if len(emitter.Files) != 2 {
+		t.Fatalf("expected exactly 2 generated types (Owner, Pet), got %v", mapKeys(emitter.Files))
+	}
+	pet, ok := emitter.Files["Pet"]
+	if !ok {
+		t.Fatalf("expected a Pet type, got %v", mapKeys(emitter.Files))
+	}
+	if !strings.Contains(pet, "Owner *Owner") {
+		t.Fatalf("expected Pet.Owner to be typed *Owner, got: %s", pet)
+	}
+}
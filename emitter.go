@@ -0,0 +1,93 @@
+package slipscheme
+
+import (
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+)
+
+// Emitter writes a single generated type's source to its destination, be it
+// a file, stdout, or an in-memory buffer. SchemaProcessor uses whichever
+// Emitter it's given instead of talking to the filesystem directly, so
+// slipscheme can be embedded in another program's generation pipeline.
+type Emitter interface {
+	WriteType(name, code string) error
+}
+
+// gofmtSource runs code through go/format, returning it unchanged if fmt is
+// false or the code fails to parse.
+func gofmtSource(code string, fmt bool) (string, error) {
+	if !fmt {
+		return code, nil
+	}
+	formatted, err := format.Source([]byte(code))
+	if err != nil {
+		return "", err
+	}
+	return string(formatted), nil
+}
+
+// FileEmitter writes each generated type to its own "<name>.go" file under
+// Dir, the original behavior of the CLI tool.
+type FileEmitter struct {
+	Dir       string
+	Overwrite bool
+	Fmt       bool
+}
+
+// WriteType writes code to Dir/name.go, skipping existing files unless
+// Overwrite is set.
+func (e *FileEmitter) WriteType(name, code string) error {
+	file := path.Join(e.Dir, fmt.Sprintf("%s.go", name))
+	if !e.Overwrite {
+		if _, err := os.Stat(file); err == nil {
+			log.Printf("File %s already exists, skipping without -overwrite", file)
+			return nil
+		}
+	}
+	formatted, err := gofmtSource(code, e.Fmt)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, []byte(formatted), 0644)
+}
+
+// StdoutEmitter prints each generated type to stdout instead of writing
+// files, for piping into another tool.
+type StdoutEmitter struct {
+	Fmt bool
+}
+
+// WriteType prints code to stdout.
+func (e *StdoutEmitter) WriteType(name, code string) error {
+	formatted, err := gofmtSource(code, e.Fmt)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Print(formatted)
+	return err
+}
+
+// MemoryEmitter collects generated type source in memory, keyed by type
+// name, instead of writing it anywhere. This is what library callers get
+// back from Process.
+type MemoryEmitter struct {
+	Fmt   bool
+	Files map[string]string
+}
+
+// WriteType stores code under Files[name].
+func (e *MemoryEmitter) WriteType(name, code string) error {
+	formatted, err := gofmtSource(code, e.Fmt)
+	if err != nil {
+		return err
+	}
+	if e.Files == nil {
+		e.Files = make(map[string]string)
+	}
+	e.Files[name] = formatted
+	return nil
+}
@@ -0,0 +1,72 @@
+package slipscheme
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMultiHopRefResolution covers a $ref resolved from a document that
+// itself contains a bare "#/..." ref: the bare ref must resolve against the
+// document it was fetched from, not the document that originally pointed at
+// it.
+func TestMultiHopRefResolution(t *testing.T) {
+	dir := t.TempDir()
+	mainPath := filepath.Join(dir, "main.json")
+	commonPath := filepath.Join(dir, "common.json")
+
+	if err := os.WriteFile(mainPath, []byte(`{
+		"type": "object",
+		"properties": {
+			"address": {"$ref": "common.json#/definitions/Address"}
+		}
+	}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(commonPath, []byte(`{
+		"definitions": {
+			"Address": {
+				"type": "object",
+				"properties": {
+					"street": {"type": "string"},
+					"coordinates": {"$ref": "#/definitions/Coordinates"}
+				}
+			},
+			"Coordinates": {
+				"type": "object",
+				"properties": {
+					"lat": {"type": "number"},
+					"lng": {"type": "number"}
+				}
+			}
+		}
+	}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	emitter := &MemoryEmitter{}
+	processor := NewProcessor(Options{PackageName: "model", Emitter: emitter, BaseDir: dir})
+	if err := processor.Load([]string{mainPath}); err != nil {
+		t.Fatal(err)
+	}
+	if err := processor.Process(context.Background()); err != nil {
+		t.Fatalf("Process: %s", err)
+	}
+
+	if _, ok := emitter.Files["Coordinates"]; !ok {
+		t.Fatalf("expected a Coordinates type to be generated, got %v", mapKeys(emitter.Files))
+	}
+	if !strings.Contains(emitter.Files["Coordinates"], "Lat") {
+		t.Fatalf("Coordinates type missing Lat field: %s", emitter.Files["Coordinates"])
+	}
+}
+
+func mapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -1,31 +1,142 @@
-package main
+// Package slipscheme converts JSON Schema documents into Go struct
+// definitions.
+package slipscheme
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
-	"flag"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"net/http"
 	"os"
-	"os/exec"
 	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Schema represents JSON schema.
 type Schema struct {
-	Title             string             `json:"title,omitempty"`
-	ID                string             `json:"id,omitempty"`
-	Type              SchemaType         `json:"type,omitempty"`
-	Description       string             `json:"description,omitempty"`
-	Definitions       map[string]*Schema `json:"definitions,omitempty"`
-	Properties        map[string]*Schema `json:"properties,omitempty"`
-	PatternProperties map[string]*Schema `json:"patternProperties,omitempty"`
-	Ref               string             `json:"$ref,omitempty"`
-	Items             *Schema            `json:"items,omitempty"`
+	Title                string                `json:"title,omitempty"`
+	ID                   string                `json:"id,omitempty"`
+	Type                 SchemaType            `json:"type,omitempty"`
+	Description          string                `json:"description,omitempty"`
+	Definitions          map[string]*Schema    `json:"definitions,omitempty"`
+	Defs                 map[string]*Schema    `json:"$defs,omitempty"`
+	Properties           map[string]*Schema    `json:"properties,omitempty"`
+	PatternProperties    map[string]*Schema    `json:"patternProperties,omitempty"`
+	AdditionalProperties *AdditionalProperties `json:"additionalProperties,omitempty"`
+	Required             []string              `json:"required,omitempty"`
+	Ref                  string                `json:"$ref,omitempty"`
+	Items                *Schema               `json:"items,omitempty"`
+	Enum                 []interface{}         `json:"enum,omitempty"`
+	Const                interface{}           `json:"const,omitempty"`
+	OneOf                []*Schema             `json:"oneOf,omitempty"`
+	AnyOf                []*Schema             `json:"anyOf,omitempty"`
+	AllOf                []*Schema             `json:"allOf,omitempty"`
+	Format               string                `json:"format,omitempty"`
+	Pattern              string                `json:"pattern,omitempty"`
+	Nullable             bool                  `json:"-"`
+	MinLength            *int                  `json:"minLength,omitempty"`
+	MaxLength            *int                  `json:"maxLength,omitempty"`
+	Minimum              *float64              `json:"minimum,omitempty"`
+	Maximum              *float64              `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64              `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64              `json:"exclusiveMaximum,omitempty"`
+	MinItems             *int                  `json:"minItems,omitempty"`
+	MaxItems             *int                  `json:"maxItems,omitempty"`
+	UniqueItems          bool                  `json:"uniqueItems,omitempty"`
+	// Discriminator is the OpenAPI keyword identifying which property of a
+	// oneOf/anyOf schema selects the concrete type.
+	Discriminator *Discriminator `json:"discriminator,omitempty"`
+	// origin identifies the document and JSON pointer a $ref-resolved schema
+	// was copied from (docURI + "#" + pointer), so that two different $refs
+	// pointing at the same node - or a $ref and the named component it
+	// points at - reuse one generated type instead of producing a duplicate.
+	origin string
+}
+
+// Discriminator models the OpenAPI "discriminator" keyword used alongside
+// oneOf/anyOf to say which property selects the concrete schema, and how
+// its values map to schema names/refs.
+type Discriminator struct {
+	PropertyName string            `json:"propertyName,omitempty"`
+	Mapping      map[string]string `json:"mapping,omitempty"`
+}
+
+// AdditionalProperties models the JSON Schema "additionalProperties" keyword,
+// which is either a boolean allowing/disallowing extra properties, or a
+// schema that constrains the type of those extra properties.
+type AdditionalProperties struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+// UnmarshalJSON parses "additionalProperties" as either a bool or a schema.
+func (a *AdditionalProperties) UnmarshalJSON(b []byte) error {
+	trimmed := strings.TrimSpace(string(b))
+	if trimmed == "true" || trimmed == "false" {
+		return json.Unmarshal(b, &a.Allowed)
+	}
+	a.Allowed = true
+	a.Schema = &Schema{}
+	return json.Unmarshal(b, a.Schema)
+}
+
+// MarshalJSON serializes "additionalProperties" back to its bool-or-schema form.
+func (a *AdditionalProperties) MarshalJSON() ([]byte, error) {
+	if a.Schema != nil {
+		return json.Marshal(a.Schema)
+	}
+	return json.Marshal(a.Allowed)
+}
+
+// UnmarshalJSON allows the "type" keyword to be either a single string (the
+// common case) or an array of strings as permitted by newer JSON Schema
+// drafts, where a "null" entry alongside another type marks the schema as
+// nullable. It also accepts OpenAPI/Swagger's sibling "nullable": true
+// keyword, which expresses the same thing without a type array.
+func (schema *Schema) UnmarshalJSON(b []byte) error {
+	type schemaAlias Schema
+	aux := &struct {
+		Type     json.RawMessage `json:"type,omitempty"`
+		Nullable bool            `json:"nullable,omitempty"`
+		*schemaAlias
+	}{
+		schemaAlias: (*schemaAlias)(schema),
+	}
+	if err := json.Unmarshal(b, aux); err != nil {
+		return err
+	}
+	if aux.Nullable {
+		schema.Nullable = true
+	}
+	if len(aux.Type) == 0 {
+		return nil
+	}
+	var single SchemaType
+	if err := single.UnmarshalJSON(aux.Type); err == nil {
+		schema.Type = single
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(aux.Type, &list); err != nil {
+		return fmt.Errorf("invalid \"type\" keyword: %s", aux.Type)
+	}
+	for _, t := range list {
+		if t == "null" {
+			schema.Nullable = true
+			continue
+		}
+		var st SchemaType
+		if err := st.UnmarshalJSON([]byte(`"` + t + `"`)); err != nil {
+			return err
+		}
+		schema.Type = st
+	}
+	return nil
 }
 
 func (schema *Schema) String() string {
@@ -36,8 +147,6 @@ func (schema *Schema) String() string {
 	return string(bytes)
 }
 
-var anonymousObjectCount = 0
-
 // Name will attempt to determine the name of the Schema element using
 // the Title or ID (in that order)
 func (schema *Schema) Name() string {
@@ -135,46 +244,73 @@ func getReferenceName(file string) string {
 	return name
 }
 
-func main() {
-	outputDir := flag.String("dir", "tmp", "output directory for go files.")
-	pkgName := flag.String("pkg", "model", "package namespace for go files")
-	overwrite := flag.Bool("overwrite", true, "force overwriting existing go files")
-	stdout := flag.Bool("stdout", false, "print go code to stdout rather than files")
-	format := flag.Bool("fmt", true, "pass code through gofmt")
-	comments := flag.Bool("comments", true, "enable/disable print comments")
-
-	flag.Parse()
+// Options configures a SchemaProcessor built via NewProcessor.
+type Options struct {
+	OutputDir   string
+	PackageName string
+	Overwrite   bool
+	Stdout      bool
+	Fmt         bool
+	Comment     bool
+	Validate    bool
+	UUIDType    string
+	BaseDir     string
+	OfflineMode bool
+	RefLoader   RefLoader
+	Emitter     Emitter
+	// NameStrategy derives names for anonymous schema nodes. Defaults to
+	// DefaultNameStrategy, which names a node after its JSON pointer path.
+	NameStrategy NameStrategy
+	// NameOverrides maps a schema node's JSON pointer path (e.g.
+	// "/properties/user/properties/address") to the exact Go type name to
+	// use for it, bypassing NameStrategy for that node.
+	NameOverrides map[string]string
+	// InputFormat selects how Load interprets each file. Defaults to
+	// InputAuto, which detects the format per file.
+	InputFormat InputFormat
+}
 
-	if _, err := os.Stat(*outputDir); os.IsNotExist(err) {
-		os.MkdirAll(*outputDir, 0755)
+// NewProcessor builds a SchemaProcessor from Options. This is the preferred
+// entry point for embedding slipscheme as a library; the CLI in
+// cmd/slipscheme is a thin wrapper around it.
+func NewProcessor(opts Options) *SchemaProcessor {
+	return &SchemaProcessor{
+		OutputDir:     opts.OutputDir,
+		PackageName:   opts.PackageName,
+		Overwrite:     opts.Overwrite,
+		Stdout:        opts.Stdout,
+		Fmt:           opts.Fmt,
+		Comment:       opts.Comment,
+		Validate:      opts.Validate,
+		UUIDType:      opts.UUIDType,
+		BaseDir:       opts.BaseDir,
+		OfflineMode:   opts.OfflineMode,
+		RefLoader:     opts.RefLoader,
+		Emitter:       opts.Emitter,
+		NameStrategy:  opts.NameStrategy,
+		NameOverrides: opts.NameOverrides,
+		InputFormat:   opts.InputFormat,
 	}
+}
 
+// Process runs schemas through a throwaway SchemaProcessor backed by a
+// MemoryEmitter and returns each generated type's source keyed by type
+// name, so callers can embed slipscheme in their own generation pipeline
+// (e.g. a go generate tool) without shelling out to the CLI or touching
+// disk.
+func Process(ctx context.Context, schemas map[string]*Schema) (map[string]string, error) {
+	emitter := &MemoryEmitter{Fmt: true}
 	processor := &SchemaProcessor{
-		OutputDir:   *outputDir,
-		PackageName: *pkgName,
-		Overwrite:   *overwrite,
-		Stdout:      *stdout,
-		Fmt:         *format,
-		Comment:     *comments,
-	}
-
-	args := flag.Args()
-	if len(args) == 0 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <schema file> [<schema file> ...]\n", os.Args[0])
-		flag.PrintDefaults()
-		os.Exit(1)
+		PackageName: "model",
+		Fmt:         true,
+		Comment:     true,
+		Emitter:     emitter,
+		schemas:     schemas,
 	}
-	files := getFileList(args)
-	err := processor.Load(files)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-		os.Exit(1)
-	}
-	err = processor.Process()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-		os.Exit(1)
+	if err := processor.Process(ctx); err != nil {
+		return nil, err
 	}
+	return emitter.Files, nil
 }
 
 // SchemaProcessor object used to convert json schemas to golang structs
@@ -185,13 +321,101 @@ type SchemaProcessor struct {
 	Stdout      bool
 	Fmt         bool
 	Comment     bool
-	schemas     map[string]*Schema
-	processed   map[string]bool
+	// UUIDType is the Go type generated for string schemas with
+	// `"format": "uuid"`. Defaults to "string" when empty.
+	UUIDType string
+	// BaseDir resolves relative filesystem $ref targets that weren't
+	// passed on the initial file list. Defaults to the current directory.
+	BaseDir string
+	// OfflineMode, when true, makes the default RefLoader refuse to fetch
+	// http(s) references instead of reaching out to the network.
+	OfflineMode bool
+	// RefLoader fetches the raw bytes of any $ref target not already
+	// loaded from the initial file list. Defaults to a loader that
+	// understands file, http and https URIs.
+	RefLoader RefLoader
+	// Validate, when true, additionally emits a Validate() error method
+	// for each generated struct, enforcing the schema's constraints.
+	Validate bool
+	// Emitter controls where generated type source is written. Defaults
+	// to a StdoutEmitter or FileEmitter depending on Stdout.
+	Emitter Emitter
+	// NameStrategy derives names for anonymous schema nodes. Defaults to
+	// DefaultNameStrategy, which names a node after its JSON pointer path.
+	NameStrategy NameStrategy
+	// NameOverrides maps a schema node's JSON pointer path to the exact Go
+	// type name to use for it, bypassing NameStrategy for that node.
+	NameOverrides map[string]string
+	// InputFormat selects how Load interprets each file. Defaults to
+	// InputAuto, which detects the format per file.
+	InputFormat   InputFormat
+	schemas       map[string]*Schema
+	processed     map[string]bool
+	rawDocs       map[string][]byte
+	docCache      map[string]interface{}
+	assignedNames map[string]string
+	originTypes   map[string]string
+}
+
+// InputFormat selects how Load interprets each file it's given.
+type InputFormat string
+
+const (
+	// InputAuto detects the format of each file from its top-level keys.
+	InputAuto InputFormat = ""
+	// InputJSONSchema treats each file as a standalone JSON Schema document.
+	InputJSONSchema InputFormat = "jsonschema"
+	// InputOpenAPI treats each file as an OpenAPI 3.x or Swagger 2.0
+	// document, generating a type for every schema found under
+	// components/schemas (OpenAPI) or definitions (Swagger).
+	InputOpenAPI InputFormat = "openapi"
+	// InputAsyncAPI treats each file as an AsyncAPI document, generating a
+	// type for every schema found under components/schemas.
+	InputAsyncAPI InputFormat = "asyncapi"
+)
+
+// detectInputFormat inspects a document's top-level keys to tell an OpenAPI
+// 3.x/Swagger 2.0 document, an AsyncAPI document and a plain JSON Schema
+// document apart.
+func detectInputFormat(raw []byte) InputFormat {
+	var probe struct {
+		OpenAPI  string `json:"openapi"`
+		Swagger  string `json:"swagger"`
+		AsyncAPI string `json:"asyncapi"`
+	}
+	if err := json.Unmarshal(raw, &probe); err == nil {
+		switch {
+		case probe.AsyncAPI != "":
+			return InputAsyncAPI
+		case probe.OpenAPI != "" || probe.Swagger != "":
+			return InputOpenAPI
+		}
+	}
+	return InputJSONSchema
+}
+
+// apiSchemaContainer locates the map of named schemas within an OpenAPI
+// 3.x/AsyncAPI document (components/schemas) or a Swagger 2.0 document
+// (definitions).
+func apiSchemaContainer(doc map[string]interface{}) (schemas map[string]interface{}, pointerPrefix string) {
+	if components, ok := doc["components"].(map[string]interface{}); ok {
+		if schemas, ok := components["schemas"].(map[string]interface{}); ok {
+			return schemas, "/components/schemas"
+		}
+	}
+	if definitions, ok := doc["definitions"].(map[string]interface{}); ok {
+		return definitions, "/definitions"
+	}
+	return nil, ""
 }
 
-// Load will read a list of json schema files and concert to schema objects
-func (s *SchemaProcessor) Load(files []string) error {
+// Load will read a list of json schema files and concert to schema objects.
+// Entries may be glob patterns; they're expanded against the filesystem
+// before being read.
+func (s *SchemaProcessor) Load(args []string) error {
+	files := getFileList(args)
 	s.schemas = make(map[string]*Schema)
+	s.rawDocs = make(map[string][]byte)
 	for _, file := range files {
 		var fh *os.File
 		var err error
@@ -206,6 +430,20 @@ func (s *SchemaProcessor) Load(files []string) error {
 		}
 
 		reference := getReferenceName(file)
+		s.rawDocs[reference] = b
+
+		format := s.InputFormat
+		if format == InputAuto {
+			format = detectInputFormat(b)
+		}
+
+		if format == InputOpenAPI || format == InputAsyncAPI {
+			if err := s.loadAPIDocument(b, reference, format); err != nil {
+				return err
+			}
+			continue
+		}
+
 		schema, err := s.LoadSchema(b, reference)
 		if err != nil {
 			return err
@@ -216,11 +454,258 @@ func (s *SchemaProcessor) Load(files []string) error {
 	return nil
 }
 
-// Process will read a list of json schema files, parse them
-// and write them to the OutputDir
-func (s *SchemaProcessor) Process() error {
+// loadAPIDocument extracts every named schema from an OpenAPI 3.x/Swagger
+// 2.0 or AsyncAPI document and registers each one as its own top-level
+// schema, the same shape Load already gives a set of standalone JSON
+// Schema files, so the rest of the pipeline (ref resolution, naming,
+// codegen) doesn't need to know the input wasn't plain JSON Schema.
+func (s *SchemaProcessor) loadAPIDocument(data []byte, reference string, format InputFormat) error {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	schemas, pointerPrefix := apiSchemaContainer(doc)
+	if schemas == nil {
+		return fmt.Errorf("%s: no schemas found under components/schemas or definitions (detected as %s)", reference, format)
+	}
+
+	for name, node := range schemas {
+		raw, err := json.Marshal(node)
+		if err != nil {
+			return err
+		}
+		schema, err := s.LoadSchema(raw, reference)
+		if err != nil {
+			return err
+		}
+		if schema.Name() == "" {
+			schema.Title = name
+		}
+		schema.origin = reference + "#" + pointerPrefix + "/" + name
+
+		key := name
+		if _, taken := s.schemas[key]; taken {
+			key = reference + "_" + name
+		}
+		s.schemas[key] = schema
+	}
+	return nil
+}
+
+// RefLoader fetches the raw bytes of a schema document named by a $ref. The
+// default composite loader understands file, http and https URIs; callers
+// may supply their own implementation to add custom schemes or caching.
+type RefLoader interface {
+	Load(uri string) ([]byte, error)
+}
+
+// FileRefLoader loads schema documents from the local filesystem, resolving
+// relative paths against BaseDir.
+type FileRefLoader struct {
+	BaseDir string
+}
+
+// Load reads uri (optionally prefixed with "file://") from disk.
+func (l *FileRefLoader) Load(uri string) ([]byte, error) {
+	p := strings.TrimPrefix(uri, "file://")
+	if !filepath.IsAbs(p) && l.BaseDir != "" {
+		p = filepath.Join(l.BaseDir, p)
+	}
+	return ioutil.ReadFile(p)
+}
+
+// HTTPRefLoader fetches schema documents over http or https.
+type HTTPRefLoader struct {
+	Client  *http.Client
+	Offline bool
+}
+
+// Load fetches uri over HTTP(S), or fails immediately in offline mode.
+func (l *HTTPRefLoader) Load(uri string) ([]byte, error) {
+	if l.Offline {
+		return nil, fmt.Errorf("offline mode: refusing to fetch remote reference %s", uri)
+	}
+	client := l.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Get(uri)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: unexpected status %s", uri, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// CompositeRefLoader dispatches to a scheme-specific RefLoader, falling back
+// to the filesystem for relative paths with no scheme.
+type CompositeRefLoader struct {
+	File *FileRefLoader
+	HTTP *HTTPRefLoader
+}
+
+// Load routes uri to the File or HTTP loader based on its scheme.
+func (l *CompositeRefLoader) Load(uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://") {
+		return l.HTTP.Load(uri)
+	}
+	return l.File.Load(uri)
+}
+
+func (s *SchemaProcessor) refLoader() RefLoader {
+	if s.RefLoader != nil {
+		return s.RefLoader
+	}
+	return &CompositeRefLoader{
+		File: &FileRefLoader{BaseDir: s.BaseDir},
+		HTTP: &HTTPRefLoader{Offline: s.OfflineMode},
+	}
+}
+
+// loadDoc returns the parsed JSON document identified by uri, fetching and
+// caching it on first use. Documents from the initial file list are served
+// from memory; anything else goes through the configured RefLoader.
+func (s *SchemaProcessor) loadDoc(uri string) (interface{}, error) {
+	if doc, ok := s.docCache[uri]; ok {
+		return doc, nil
+	}
+	raw, ok := s.rawDocs[uri]
+	if !ok {
+		fetched, err := s.refLoader().Load(uri)
+		if err != nil {
+			return nil, err
+		}
+		raw = fetched
+	}
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	if s.docCache == nil {
+		s.docCache = make(map[string]interface{})
+	}
+	if s.rawDocs == nil {
+		s.rawDocs = make(map[string][]byte)
+	}
+	s.rawDocs[uri] = raw
+	s.docCache[uri] = doc
+	return doc, nil
+}
+
+// resolveRef looks up the document and JSON Pointer fragment named by ref
+// (as seen from fromReference) and decodes the pointed-at node into a fresh
+// Schema.
+func (s *SchemaProcessor) resolveRef(fromReference, ref string) (*Schema, error) {
+	docURI, pointer := splitRef(fromReference, ref)
+	doc, err := s.loadDoc(docURI)
+	if err != nil {
+		return nil, fmt.Errorf("resolving $ref %q from %s: %s", ref, fromReference, err)
+	}
+	node, err := jsonPointerLookup(doc, pointer)
+	if err != nil {
+		return nil, fmt.Errorf("resolving $ref %q from %s: %s", ref, fromReference, err)
+	}
+	raw, err := json.Marshal(node)
+	if err != nil {
+		return nil, err
+	}
+	resolved := &Schema{}
+	if err := json.Unmarshal(raw, resolved); err != nil {
+		return nil, err
+	}
+	if resolved.Name() == "" {
+		resolved.Title = lastPointerSegment(pointer)
+	}
+	resolved.origin = docURI + "#" + pointer
+	return resolved, nil
+}
+
+// lastPointerSegment returns the final, unescaped segment of an RFC 6901
+// JSON Pointer, e.g. "/components/schemas/Owner" yields "Owner". It is used
+// to name a $ref-resolved node after the component it points at, so that a
+// schema referenced from several places is only ever generated once instead
+// of once per reference.
+func lastPointerSegment(pointer string) string {
+	segments := strings.Split(strings.Trim(pointer, "/"), "/")
+	last := segments[len(segments)-1]
+	last = strings.ReplaceAll(last, "~1", "/")
+	last = strings.ReplaceAll(last, "~0", "~")
+	return last
+}
+
+// splitRef splits a "<document>#<json-pointer>" reference (or a bare
+// "#<json-pointer>" referring back to fromReference) into the document URI
+// it targets and the pointer within it.
+func splitRef(fromReference, ref string) (docURI string, pointer string) {
+	parts := strings.SplitN(ref, "#", 2)
+	docURI = parts[0]
+	if len(parts) == 2 {
+		pointer = parts[1]
+	}
+	if docURI == "" {
+		docURI = fromReference
+	}
+	return docURI, pointer
+}
+
+// jsonPointerLookup evaluates an RFC 6901 JSON Pointer against a generic
+// JSON document, as produced by unmarshaling into interface{}.
+func jsonPointerLookup(doc interface{}, pointer string) (interface{}, error) {
+	if pointer == "" {
+		return doc, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must be empty or start with \"/\"", pointer)
+	}
+	var unescaper = strings.NewReplacer("~1", "/", "~0", "~")
+	current := doc
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = unescaper.Replace(token)
+		switch node := current.(type) {
+		case map[string]interface{}:
+			next, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("JSON pointer %q: key %q not found", pointer, token)
+			}
+			current = next
+		case []interface{}:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, fmt.Errorf("JSON pointer %q: invalid array index %q", pointer, token)
+			}
+			current = node[idx]
+		default:
+			return nil, fmt.Errorf("JSON pointer %q: cannot descend into a scalar at %q", pointer, token)
+		}
+	}
+	return current, nil
+}
+
+// Process parses the schemas loaded via Load (or set directly for library
+// use) and writes the resulting Go types out through the configured
+// Emitter. ctx is checked between schemas so a caller can cancel a large run.
+func (s *SchemaProcessor) Process(ctx context.Context) error {
+	if s.Validate {
+		if err := s.writeValidationError(); err != nil {
+			return err
+		}
+	}
+	keys := make([]string, 0, len(s.schemas))
+	for key := range s.schemas {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
 	var targetSchemas []*Schema
-	for key, schema := range s.schemas {
+	for _, key := range keys {
+		schema := s.schemas[key]
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		targetSchema, err := s.ParseSchema(key, schema)
 		if err != nil {
 			return err
@@ -228,6 +713,9 @@ func (s *SchemaProcessor) Process() error {
 		targetSchemas = append(targetSchemas, targetSchema)
 	}
 	for _, targetSchema := range targetSchemas {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		_, err := s.processSchema(targetSchema)
 		if err != nil {
 			return err
@@ -236,6 +724,63 @@ func (s *SchemaProcessor) Process() error {
 	return nil
 }
 
+// writeValidationError emits the shared ValidationError/FieldError types that
+// every generated Validate() method aggregates its failures into.
+func (s *SchemaProcessor) writeValidationError() error {
+	code := `// FieldError is a single validation failure at Path, a JSON-pointer-style
+// location such as "/items/3/name".
+type FieldError struct {
+    Path    string
+    Message string
+}
+
+func (e *FieldError) Error() string {
+    return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidationError aggregates the FieldErrors found while validating a
+// generated struct.
+type ValidationError struct {
+    Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+    parts := make([]string, len(e.Errors))
+    for i, fe := range e.Errors {
+        parts[i] = fe.Error()
+    }
+    return strings.Join(parts, "; ")
+}
+
+// Add records a single field failure at path.
+func (e *ValidationError) Add(path, message string) {
+    e.Errors = append(e.Errors, FieldError{Path: path, Message: message})
+}
+
+// merge folds the FieldErrors of a nested Validate() call into e, prefixing
+// each nested path with parentPath.
+func (e *ValidationError) merge(parentPath string, err error) {
+    if err == nil {
+        return
+    }
+    if nested, ok := err.(*ValidationError); ok {
+        for _, fe := range nested.Errors {
+            e.Add(parentPath+fe.Path, fe.Message)
+        }
+        return
+    }
+    e.Add(parentPath, err.Error())
+}
+
+// HasErrors reports whether any failures were recorded.
+func (e *ValidationError) HasErrors() bool {
+    return len(e.Errors) > 0
+}
+
+`
+	return s.writeGoCode("ValidationError", code, "fmt", "strings")
+}
+
 func updateDefinitionTitles(schema *Schema) {
 	if schema.Definitions != nil {
 		for k, v := range schema.Definitions {
@@ -245,6 +790,14 @@ func updateDefinitionTitles(schema *Schema) {
 			updateDefinitionTitles(v)
 		}
 	}
+	if schema.Defs != nil {
+		for k, v := range schema.Defs {
+			if v.Name() == "" {
+				v.Title = k
+			}
+			updateDefinitionTitles(v)
+		}
+	}
 }
 
 func updateReferencePath(schema *Schema, reference string) {
@@ -253,6 +806,11 @@ func updateReferencePath(schema *Schema, reference string) {
 			updateReferencePath(v, reference)
 		}
 	}
+	if schema.Defs != nil {
+		for _, v := range schema.Defs {
+			updateReferencePath(v, reference)
+		}
+	}
 	if schema.Properties != nil {
 		for _, v := range schema.Properties {
 			updateReferencePath(v, reference)
@@ -263,6 +821,18 @@ func updateReferencePath(schema *Schema, reference string) {
 			updateReferencePath(v, reference)
 		}
 	}
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+		updateReferencePath(schema.AdditionalProperties.Schema, reference)
+	}
+	for _, v := range schema.OneOf {
+		updateReferencePath(v, reference)
+	}
+	for _, v := range schema.AnyOf {
+		updateReferencePath(v, reference)
+	}
+	for _, v := range schema.AllOf {
+		updateReferencePath(v, reference)
+	}
 	if schema.Items != nil {
 		updateReferencePath(schema.Items, reference)
 	}
@@ -300,40 +870,16 @@ func (s *SchemaProcessor) ParseSchema(reference string, schema *Schema) (*Schema
 
 func (s *SchemaProcessor) resolveRefs(reference string, schema *Schema) error {
 	if schema.Ref != "" {
-		schemaPath := strings.Split(schema.Ref, "/")
-		var ctx interface{}
-		ctx = schema
-		for _, part := range schemaPath {
-			if part == "#" {
-				return errors.New("Invalid reference point - please make sure references have file names specified - " + reference)
-			} else if strings.HasSuffix(part, "#") {
-				var referenceName = part[:len(part)-1]
-				var referenceSchema, found = s.schemas[referenceName]
-				if !found {
-					return errors.New("Invalid reference file - please make sure the referenced files are in the processing list - " + reference + " ? " + referenceName)
-				}
-				ctx = referenceSchema
-			} else if part == "definitions" {
-				ctx = ctx.(*Schema).Definitions
-			} else if part == "properties" {
-				ctx = ctx.(*Schema).Properties
-			} else if part == "patternProperties" {
-				ctx = ctx.(*Schema).PatternProperties
-			} else if part == "items" {
-				ctx = ctx.(*Schema).Items
-			} else {
-				if cast, ok := ctx.(map[string]*Schema); ok {
-					ctx = cast[part]
-				}
-			}
-		}
-		if cast, ok := ctx.(*Schema); ok {
-			*schema = *cast
-		}
-		err := s.resolveRefs(reference, schema)
+		docURI, _ := splitRef(reference, schema.Ref)
+		resolved, err := s.resolveRef(reference, schema.Ref)
 		if err != nil {
 			return err
 		}
+		*schema = *resolved
+		// Any $ref found inside the resolved node (including a bare
+		// "#/..." one) is relative to docURI, the document it actually
+		// came from, not the document that referenced it.
+		return s.resolveRefs(docURI, schema)
 	}
 
 	if schema.Definitions != nil {
@@ -344,6 +890,14 @@ func (s *SchemaProcessor) resolveRefs(reference string, schema *Schema) error {
 			}
 		}
 	}
+	if schema.Defs != nil {
+		for _, v := range schema.Defs {
+			err := s.resolveRefs(reference, v)
+			if err != nil {
+				return err
+			}
+		}
+	}
 	if schema.Properties != nil {
 		for _, v := range schema.Properties {
 			err := s.resolveRefs(reference, v)
@@ -360,6 +914,27 @@ func (s *SchemaProcessor) resolveRefs(reference string, schema *Schema) error {
 			}
 		}
 	}
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+		err := s.resolveRefs(reference, schema.AdditionalProperties.Schema)
+		if err != nil {
+			return err
+		}
+	}
+	for _, v := range schema.OneOf {
+		if err := s.resolveRefs(reference, v); err != nil {
+			return err
+		}
+	}
+	for _, v := range schema.AnyOf {
+		if err := s.resolveRefs(reference, v); err != nil {
+			return err
+		}
+	}
+	for _, v := range schema.AllOf {
+		if err := s.resolveRefs(reference, v); err != nil {
+			return err
+		}
+	}
 	if schema.Items != nil {
 		err := s.resolveRefs(reference, schema.Items)
 		if err != nil {
@@ -369,41 +944,160 @@ func (s *SchemaProcessor) resolveRefs(reference string, schema *Schema) error {
 	return nil
 }
 
-func (s *SchemaProcessor) setTitle(reference string, schema *Schema) {
-	if schema.Definitions != nil {
-		for k, v := range schema.Definitions {
-			if v.Name() == "" {
-				v.Title = k
-			}
-			s.setTitle(reference, v)
+// NameStrategy assigns a Go-export-friendly name to a schema node that has
+// no title/id of its own, given the JSON-pointer-style path it was found
+// at (e.g. "/properties/user/properties/address").
+type NameStrategy interface {
+	Name(path string, schema *Schema) string
+}
+
+// DefaultNameStrategy derives a name from the schema's own path, so that
+// anonymous schemas get a deterministic, collision-resistant name without
+// relying on mutable package-level counters.
+type DefaultNameStrategy struct{}
+
+// Name camelCases each path segment (including the "properties"/"items"/
+// "definitions" keywords themselves) and concatenates them under a "Root"
+// prefix.
+func (DefaultNameStrategy) Name(path string, schema *Schema) string {
+	name := "Root"
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if segment == "" {
+			continue
 		}
+		name += camelCase(segment)
 	}
-	if schema.Properties != nil {
-		for k, v := range schema.Properties {
-			if v.Name() == "" {
-				v.Title = k
-			}
-			s.setTitle(reference, v)
+	return name
+}
+
+func (s *SchemaProcessor) nameStrategy() NameStrategy {
+	if s.NameStrategy != nil {
+		return s.NameStrategy
+	}
+	return DefaultNameStrategy{}
+}
+
+// disambiguate returns candidate, or a variant of it salted with path's
+// parent segment (and, failing that, a numeric suffix) if candidate is
+// already assigned to a different path. This is what lets two unrelated
+// schemas that both happen to be named e.g. "metadata" end up as distinct
+// Go types instead of silently overwriting the same generated file.
+func (s *SchemaProcessor) disambiguate(candidate, path string) string {
+	if candidate == "" {
+		return candidate
+	}
+	if s.assignedNames == nil {
+		s.assignedNames = make(map[string]string)
+	}
+	name := candidate
+	for attempt := 0; ; attempt++ {
+		existingPath, taken := s.assignedNames[name]
+		if !taken || existingPath == path {
+			s.assignedNames[name] = path
+			return name
 		}
+		parent := parentSegment(path)
+		if parent == "" {
+			name = fmt.Sprintf("%sAt%d", candidate, attempt)
+			continue
+		}
+		name = candidate + camelCase(parent)
+		candidate = name
 	}
-	if schema.PatternProperties != nil {
-		for k, v := range schema.PatternProperties {
-			if v.Name() == "" {
-				v.Title = k
-			}
-			s.setTitle(reference, v)
+}
+
+// parentSegment returns the nearest ancestor key in path that isn't one of
+// the structural JSON Schema keywords, e.g. parentSegment of
+// "/properties/user/properties/address" is "user".
+func parentSegment(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	structural := map[string]bool{
+		"properties": true, "definitions": true, "$defs": true,
+		"patternProperties": true, "items": true, "additionalProperties": true,
+		"oneOf": true, "anyOf": true, "allOf": true,
+	}
+	for i := len(segments) - 2; i >= 0; i-- {
+		if segments[i] == "" || structural[segments[i]] {
+			continue
+		}
+		return segments[i]
+	}
+	return ""
+}
+
+// setTitle walks schema assigning a name to every node that doesn't already
+// have one: NameOverrides wins if present for the node's path, then any
+// existing Title/ID, then the configured NameStrategy. Every assigned name
+// is run through disambiguate to keep type names unique across the whole
+// processed set.
+func (s *SchemaProcessor) setTitle(reference string, schema *Schema) {
+	s.setTitleAt(schema, "/"+reference)
+}
+
+func (s *SchemaProcessor) setTitleAt(schema *Schema, path string) {
+	if override, ok := s.NameOverrides[path]; ok {
+		schema.Title = override
+		if s.assignedNames == nil {
+			s.assignedNames = make(map[string]string)
 		}
+		s.assignedNames[override] = path
+	} else {
+		if schema.Name() == "" {
+			schema.Title = s.nameStrategy().Name(path, schema)
+		}
+		schema.Title = s.disambiguate(schema.Name(), path)
+	}
+
+	for k, v := range schema.Definitions {
+		s.setTitleAt(v, path+"/definitions/"+k)
+	}
+	for k, v := range schema.Defs {
+		s.setTitleAt(v, path+"/$defs/"+k)
+	}
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+		s.setTitleAt(schema.AdditionalProperties.Schema, path+"/additionalProperties")
+	}
+	for i, v := range schema.OneOf {
+		s.setTitleAt(v, fmt.Sprintf("%s/oneOf/%d", path, i))
+	}
+	for i, v := range schema.AnyOf {
+		s.setTitleAt(v, fmt.Sprintf("%s/anyOf/%d", path, i))
+	}
+	for i, v := range schema.AllOf {
+		s.setTitleAt(v, fmt.Sprintf("%s/allOf/%d", path, i))
+	}
+	for k, v := range schema.Properties {
+		s.setTitleAt(v, path+"/properties/"+k)
+	}
+	for k, v := range schema.PatternProperties {
+		s.setTitleAt(v, path+"/patternProperties/"+k)
 	}
 	if schema.Items != nil {
-		if schema.Items.Name() == "" {
-			if schema.Name() == "" {
-				anonymousObjectCount++
-				schema.Title = fmt.Sprintf("AnonymousObject%v", anonymousObjectCount)
-			}
-			schema.Items.Title = schema.Name() + "Item"
+		s.setTitleAt(schema.Items, path+"/items")
+	}
+}
+
+// importBlock renders a deduplicated, sorted Go import block, or "" if
+// imports is empty.
+func importBlock(imports []string) string {
+	if len(imports) == 0 {
+		return ""
+	}
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, imp := range imports {
+		if !seen[imp] {
+			seen[imp] = true
+			unique = append(unique, imp)
 		}
-		s.setTitle(reference, schema.Items)
 	}
+	sort.Strings(unique)
+	block := "import (\n"
+	for _, imp := range unique {
+		block += fmt.Sprintf("    %q\n", imp)
+	}
+	block += ")\n\n"
+	return block
 }
 
 func camelCase(name string) string {
@@ -440,7 +1134,147 @@ func (s *SchemaProcessor) structComment(schema *Schema, typeName string) string
 	return fmt.Sprintf("// %s defined from schema:\n// %s\n", typeName, prettySchema)
 }
 
+// unionBranches returns the "oneOf"/"anyOf" alternatives of a schema, if any.
+func unionBranches(schema *Schema) []*Schema {
+	if len(schema.OneOf) > 0 {
+		return schema.OneOf
+	}
+	if len(schema.AnyOf) > 0 {
+		return schema.AnyOf
+	}
+	return nil
+}
+
+// simplifyUnion collapses the common "{type: null}" + T pattern into T, since
+// that case is better represented as a pointer to T than as a tagged union.
+func simplifyUnion(branches []*Schema) *Schema {
+	if len(branches) != 2 {
+		return nil
+	}
+	nullIdx, otherIdx := -1, -1
+	for i, branch := range branches {
+		if branch.Type == NULL {
+			nullIdx = i
+		} else {
+			otherIdx = i
+		}
+	}
+	if nullIdx == -1 || otherIdx == -1 {
+		return nil
+	}
+	return branches[otherIdx]
+}
+
+// mergeAllOf composes an "allOf" schema's branches - plus any properties
+// declared alongside allOf itself - into a single object schema. Without
+// this, a pure-allOf node (which typically carries no "type" of its own)
+// would fall through every case in processSchema's type switch and resolve
+// to "interface{}", silently dropping the composition instead of merging it.
+func mergeAllOf(schema *Schema) *Schema {
+	merged := &Schema{
+		Title:       schema.Title,
+		Description: schema.Description,
+		Type:        OBJECT,
+		origin:      schema.origin,
+	}
+	branches := append(append([]*Schema{}, schema.AllOf...), &Schema{
+		Properties:           schema.Properties,
+		Required:             schema.Required,
+		AdditionalProperties: schema.AdditionalProperties,
+	})
+	for _, branch := range branches {
+		for k, v := range branch.Properties {
+			if merged.Properties == nil {
+				merged.Properties = map[string]*Schema{}
+			}
+			merged.Properties[k] = v
+		}
+		for _, r := range branch.Required {
+			if !contains(merged.Required, r) {
+				merged.Required = append(merged.Required, r)
+			}
+		}
+		if branch.AdditionalProperties != nil {
+			merged.AdditionalProperties = branch.AdditionalProperties
+		}
+	}
+	return merged
+}
+
+func contains(list []string, item string) bool {
+	for _, v := range list {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
+
+// formatType maps the JSON Schema "format" keyword to a concrete Go type,
+// returning ok=false when the format isn't one slipscheme specializes. This
+// also covers the extended formats OpenAPI layers on top of "integer",
+// "number" and "string" (int32/int64, float/double, byte/binary). "date" is
+// deliberately left as a plain string: time.Time unmarshals full RFC3339
+// timestamps, not bare dates.
+func (s *SchemaProcessor) formatType(format string) (typeName string, imports []string, ok bool) {
+	switch format {
+	case "date-time":
+		return "time.Time", []string{"time"}, true
+	case "uuid":
+		uuidType := s.UUIDType
+		if uuidType == "" {
+			uuidType = "string"
+		}
+		return uuidType, nil, true
+	case "int32":
+		return "int32", nil, true
+	case "int64":
+		return "int64", nil, true
+	case "float":
+		return "float32", nil, true
+	case "double":
+		return "float64", nil, true
+	case "byte", "binary":
+		return "[]byte", nil, true
+	}
+	return "", nil, false
+}
+
 func (s *SchemaProcessor) processSchema(schema *Schema) (typeName string, err error) {
+	if len(schema.AllOf) > 0 {
+		return s.processSchema(mergeAllOf(schema))
+	}
+	if schema.Const != nil && len(schema.Enum) == 0 {
+		schema.Enum = []interface{}{schema.Const}
+		if schema.Type == ANY {
+			if _, ok := schema.Const.(string); ok {
+				schema.Type = STRING
+			}
+		}
+	}
+	if schema.origin != "" {
+		if existing, ok := s.originTypes[schema.origin]; ok {
+			return existing, nil
+		}
+		defer func() {
+			if err == nil {
+				if s.originTypes == nil {
+					s.originTypes = make(map[string]string)
+				}
+				s.originTypes[schema.origin] = typeName
+			}
+		}()
+	}
+	if branches := unionBranches(schema); branches != nil {
+		if simplified := simplifyUnion(branches); simplified != nil {
+			subTypeName, err := s.processSchema(simplified)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("*%s", strings.TrimPrefix(subTypeName, "*")), nil
+		}
+		return s.processUnion(schema, branches)
+	}
 	if schema.Type == OBJECT {
 		typeName = camelCase(schema.Name())
 		if schema.Properties != nil {
@@ -450,16 +1284,63 @@ func (s *SchemaProcessor) processSchema(schema *Schema) (typeName string, err er
 				keys = append(keys, k)
 			}
 			sort.Strings(keys)
+			imports := []string{}
+			var patternVars []string
+			var checks []string
 			for _, k := range keys {
 				v := schema.Properties[k]
 				subTypeName, err := s.processSchema(v)
 				if err != nil {
 					return "", err
 				}
-				typeData += fmt.Sprintf("    %s %s `json:\"%s,omitempty\" yaml:\"%s,omitempty\"`\n", camelCase(k), subTypeName, k, k)
+				if strings.Contains(subTypeName, "time.Time") {
+					imports = append(imports, "time")
+				}
+				required := contains(schema.Required, k)
+				fieldType := subTypeName
+				jsonTag := k + ",omitempty"
+				if required {
+					fieldType = strings.TrimPrefix(fieldType, "*")
+					jsonTag = k
+				}
+				typeData += fmt.Sprintf("    %s %s `json:\"%s\" yaml:\"%s\"`\n", camelCase(k), fieldType, jsonTag, jsonTag)
+				if s.Validate {
+					patternVar, check := s.fieldValidation(typeName, k, v, required, fieldType)
+					if patternVar != "" {
+						patternVars = append(patternVars, patternVar)
+					}
+					if check != "" {
+						checks = append(checks, check)
+					}
+				}
+			}
+			extraType, hasExtra := "", false
+			if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+				extraType, err = s.processSchema(schema.AdditionalProperties.Schema)
+				if err != nil {
+					return "", err
+				}
+				hasExtra = true
+				typeData += fmt.Sprintf("    Extra map[string]%s `json:\"-\" yaml:\"-\"`\n", extraType)
 			}
 			typeData += "}\n\n"
-			if err := s.writeGoCode(typeName, typeData); err != nil {
+			if hasExtra {
+				imports = append(imports, "encoding/json")
+				typeData += s.additionalPropertiesCode(typeName, keys, extraType)
+			}
+			if s.Validate {
+				for _, c := range checks {
+					if strings.Contains(c, "fmt.") {
+						imports = append(imports, "fmt")
+						break
+					}
+				}
+				if len(patternVars) > 0 {
+					imports = append(imports, "regexp")
+				}
+				typeData += s.validateMethodCode(typeName, patternVars, checks)
+			}
+			if err := s.writeGoCode(typeName, typeData, imports...); err != nil {
 				return "", err
 			}
 			typeName = fmt.Sprintf("*%s", typeName)
@@ -487,6 +1368,12 @@ func (s *SchemaProcessor) processSchema(schema *Schema) (typeName string, err er
 					typeName = fmt.Sprintf("map[string]%s", subTypeName)
 				}
 			}
+		} else if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+			subTypeName, err := s.processSchema(schema.AdditionalProperties.Schema)
+			if err != nil {
+				return "", err
+			}
+			typeName = fmt.Sprintf("map[string]%s", subTypeName)
 		} else {
 			typeName = "map[string]interface{}"
 		}
@@ -518,18 +1405,379 @@ func (s *SchemaProcessor) processSchema(schema *Schema) (typeName string, err er
 	} else if schema.Type == BOOLEAN {
 		typeName = "bool"
 	} else if schema.Type == INTEGER {
-		typeName = "int"
+		if formatTypeName, _, ok := s.formatType(schema.Format); ok {
+			typeName = formatTypeName
+		} else {
+			typeName = "int"
+		}
 	} else if schema.Type == NUMBER {
-		typeName = "float64"
+		if formatTypeName, _, ok := s.formatType(schema.Format); ok {
+			typeName = formatTypeName
+		} else {
+			typeName = "float64"
+		}
 	} else if schema.Type == NULL || schema.Type == ANY {
 		typeName = "interface{}"
 	} else if schema.Type == STRING {
-		typeName = "string"
+		if formatTypeName, _, ok := s.formatType(schema.Format); ok {
+			typeName = formatTypeName
+		} else if len(schema.Enum) > 0 && schema.Name() != "" {
+			typeName, err = s.processEnum(schema)
+			if err != nil {
+				return "", err
+			}
+		} else {
+			typeName = "string"
+		}
+	}
+	if schema.Nullable && !strings.HasPrefix(typeName, "*") && typeName != "interface{}" {
+		typeName = fmt.Sprintf("*%s", typeName)
 	}
 	return
 }
 
-func (s *SchemaProcessor) writeGoCode(typeName, code string) error {
+// processEnum emits a named string type with one constant per enum value and
+// an UnmarshalJSON that rejects anything outside that set.
+func (s *SchemaProcessor) processEnum(schema *Schema) (string, error) {
+	typeName := camelCase(schema.Name())
+	typeData := fmt.Sprintf("%stype %s string\n\n", s.structComment(schema, typeName), typeName)
+	typeData += "const (\n"
+	constNames := make([]string, 0, len(schema.Enum))
+	for _, raw := range schema.Enum {
+		value, ok := raw.(string)
+		if !ok {
+			continue
+		}
+		constName := fmt.Sprintf("%s%s", typeName, camelCase(value))
+		constNames = append(constNames, constName)
+		typeData += fmt.Sprintf("    %s %s = %q\n", constName, typeName, value)
+	}
+	typeData += ")\n\n"
+	typeData += fmt.Sprintf("// UnmarshalJSON rejects any value of %s outside of its enum.\n", typeName)
+	typeData += fmt.Sprintf("func (x *%s) UnmarshalJSON(b []byte) error {\n", typeName)
+	typeData += "    var value string\n"
+	typeData += "    if err := json.Unmarshal(b, &value); err != nil {\n        return err\n    }\n"
+	typeData += fmt.Sprintf("    switch %s(value) {\n    case %s:\n        *x = %s(value)\n        return nil\n    }\n", typeName, strings.Join(constNames, ", "), typeName)
+	typeData += fmt.Sprintf("    return fmt.Errorf(\"invalid value %%q for type %s\", value)\n}\n\n", typeName)
+	if err := s.writeGoCode(typeName, typeData, "encoding/json", "fmt"); err != nil {
+		return "", err
+	}
+	return typeName, nil
+}
+
+// processUnion emits a wrapper struct for a non-trivial "oneOf"/"anyOf". Its
+// UnmarshalJSON dispatches on schema's discriminator when one is given, or
+// otherwise on whichever alternative's required fields are all present in
+// the payload - trying a full decode of each alternative in turn, as this
+// used to do unconditionally, silently picks the first alternative whose
+// fields happen to zero-value cleanly rather than the one the payload
+// actually matches.
+func (s *SchemaProcessor) processUnion(schema *Schema, branches []*Schema) (string, error) {
+	typeName := camelCase(schema.Name())
+	if typeName == "" {
+		typeName = "AnonymousUnion"
+	}
+
+	valueTypes := make([]string, 0, len(branches))
+	fieldNames := make([]string, 0, len(branches))
+	typeData := fmt.Sprintf("%stype %s struct {\n", s.structComment(schema, typeName), typeName)
+	for i, branch := range branches {
+		subTypeName, err := s.processSchema(branch)
+		if err != nil {
+			return "", err
+		}
+		valueType := strings.TrimPrefix(subTypeName, "*")
+		fieldName := fmt.Sprintf("Option%d", i)
+		valueTypes = append(valueTypes, valueType)
+		fieldNames = append(fieldNames, fieldName)
+		typeData += fmt.Sprintf("    %s *%s `json:\"-\" yaml:\"-\"`\n", fieldName, valueType)
+	}
+	typeData += "}\n\n"
+
+	if schema.Discriminator != nil && schema.Discriminator.PropertyName != "" {
+		typeData += s.discriminatedUnmarshalCode(schema, typeName, branches, valueTypes, fieldNames)
+	} else {
+		if err := s.writeUnionFieldHelper(); err != nil {
+			return "", err
+		}
+		typeData += s.requiredFieldUnmarshalCode(typeName, branches, valueTypes, fieldNames)
+	}
+
+	typeData += fmt.Sprintf("// MarshalJSON emits whichever alternative of %s is currently set.\n", typeName)
+	typeData += fmt.Sprintf("func (x %s) MarshalJSON() ([]byte, error) {\n", typeName)
+	for _, fieldName := range fieldNames {
+		typeData += fmt.Sprintf("    if x.%s != nil {\n        return json.Marshal(x.%s)\n    }\n", fieldName, fieldName)
+	}
+	typeData += "    return []byte(\"null\"), nil\n}\n\n"
+
+	if err := s.writeGoCode(typeName, typeData, "encoding/json", "fmt"); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("*%s", typeName), nil
+}
+
+// discriminatedUnmarshalCode emits an UnmarshalJSON that reads schema's
+// discriminator property and dispatches straight to the alternative it
+// names, per the OpenAPI "discriminator" keyword.
+func (s *SchemaProcessor) discriminatedUnmarshalCode(schema *Schema, typeName string, branches []*Schema, valueTypes, fieldNames []string) string {
+	property := schema.Discriminator.PropertyName
+	code := fmt.Sprintf("// UnmarshalJSON dispatches %s on its %q discriminator property.\n", typeName, property)
+	code += fmt.Sprintf("func (x *%s) UnmarshalJSON(b []byte) error {\n", typeName)
+	code += "    var discriminator struct {\n"
+	code += fmt.Sprintf("        Value string `json:%q`\n", property)
+	code += "    }\n"
+	code += "    if err := json.Unmarshal(b, &discriminator); err != nil {\n        return err\n    }\n"
+	code += "    switch discriminator.Value {\n"
+	for i, branch := range branches {
+		code += fmt.Sprintf("    case %q:\n", s.discriminatorValue(schema.Discriminator, branch))
+		code += fmt.Sprintf("        var v%d %s\n", i, valueTypes[i])
+		code += fmt.Sprintf("        if err := json.Unmarshal(b, &v%d); err != nil {\n            return err\n        }\n", i)
+		code += fmt.Sprintf("        x.%s = &v%d\n        return nil\n", fieldNames[i], i)
+	}
+	code += "    }\n"
+	code += fmt.Sprintf("    return fmt.Errorf(\"unknown %%s %%q for type %s\", %q, discriminator.Value)\n}\n\n", typeName, property)
+	return code
+}
+
+// discriminatorValue returns the discriminator value that selects branch:
+// its key in Discriminator.Mapping if one points at branch, otherwise
+// branch's own schema name, matching OpenAPI's default discriminator
+// behavior of using the referenced schema's name as the implicit mapping.
+func (s *SchemaProcessor) discriminatorValue(discriminator *Discriminator, branch *Schema) string {
+	if len(discriminator.Mapping) > 0 {
+		values := make([]string, 0, len(discriminator.Mapping))
+		for value := range discriminator.Mapping {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		for _, value := range values {
+			if lastPointerSegment(discriminator.Mapping[value]) == branch.Name() {
+				return value
+			}
+		}
+	}
+	return branch.Name()
+}
+
+// requiredFieldUnmarshalCode emits an UnmarshalJSON that decodes the raw
+// field set once and picks the first alternative whose required fields are
+// all present, falling back to a full decode attempt - in declaration
+// order, keeping the first that succeeds - for any alternative with no
+// required fields of its own to key off of.
+func (s *SchemaProcessor) requiredFieldUnmarshalCode(typeName string, branches []*Schema, valueTypes, fieldNames []string) string {
+	code := fmt.Sprintf("// UnmarshalJSON matches %s against whichever alternative's required fields are all present in the payload.\n", typeName)
+	code += fmt.Sprintf("func (x *%s) UnmarshalJSON(b []byte) error {\n", typeName)
+	code += "    var fields map[string]json.RawMessage\n"
+	code += "    if err := json.Unmarshal(b, &fields); err != nil {\n        return err\n    }\n"
+
+	var withRequired, withoutRequired []int
+	for i, branch := range branches {
+		if len(branch.Required) > 0 {
+			withRequired = append(withRequired, i)
+		} else {
+			withoutRequired = append(withoutRequired, i)
+		}
+	}
+
+	for _, i := range withRequired {
+		conds := make([]string, 0, len(branches[i].Required))
+		for _, field := range branches[i].Required {
+			conds = append(conds, fmt.Sprintf("hasUnionField(fields, %q)", field))
+		}
+		code += fmt.Sprintf("    if %s {\n", strings.Join(conds, " && "))
+		code += fmt.Sprintf("        var v%d %s\n", i, valueTypes[i])
+		code += fmt.Sprintf("        if err := json.Unmarshal(b, &v%d); err != nil {\n            return err\n        }\n", i)
+		code += fmt.Sprintf("        x.%s = &v%d\n        return nil\n", fieldNames[i], i)
+		code += "    }\n"
+	}
+	for _, i := range withoutRequired {
+		code += fmt.Sprintf("    var v%d %s\n    if err := json.Unmarshal(b, &v%d); err == nil {\n        x.%s = &v%d\n        return nil\n    }\n", i, valueTypes[i], i, fieldNames[i], i)
+	}
+	code += fmt.Sprintf("    return fmt.Errorf(\"value did not match any alternative of %s\")\n}\n\n", typeName)
+	return code
+}
+
+// writeUnionFieldHelper emits the hasUnionField helper shared by every
+// generated union's required-field dispatch, written once no matter how
+// many unions end up using it.
+func (s *SchemaProcessor) writeUnionFieldHelper() error {
+	code := "// hasUnionField reports whether fields carries name, used to match a\n" +
+		"// oneOf/anyOf payload against whichever alternative's required fields it satisfies.\n" +
+		"func hasUnionField(fields map[string]json.RawMessage, name string) bool {\n" +
+		"    _, ok := fields[name]\n" +
+		"    return ok\n" +
+		"}\n\n"
+	return s.writeGoCode("hasUnionField", code, "encoding/json")
+}
+
+// additionalPropertiesCode generates the (Un)MarshalJSON pair that lets a
+// struct with "additionalProperties: {schema}" collect unknown properties
+// into its Extra field instead of rejecting or silently dropping them.
+func (s *SchemaProcessor) additionalPropertiesCode(typeName string, knownKeys []string, extraType string) string {
+	quotedKeys := make([]string, len(knownKeys))
+	for i, k := range knownKeys {
+		quotedKeys[i] = fmt.Sprintf("%q", k)
+	}
+	code := fmt.Sprintf("// UnmarshalJSON decodes %s, collecting any property not explicitly\n// modeled above into Extra.\n", typeName)
+	code += fmt.Sprintf("func (x *%s) UnmarshalJSON(b []byte) error {\n", typeName)
+	code += fmt.Sprintf("    type alias %s\n", typeName)
+	code += "    aux := (*alias)(x)\n"
+	code += "    if err := json.Unmarshal(b, aux); err != nil {\n        return err\n    }\n"
+	code += "    var raw map[string]json.RawMessage\n"
+	code += "    if err := json.Unmarshal(b, &raw); err != nil {\n        return err\n    }\n"
+	code += fmt.Sprintf("    for _, known := range []string{%s} {\n        delete(raw, known)\n    }\n", strings.Join(quotedKeys, ", "))
+	code += "    if len(raw) == 0 {\n        return nil\n    }\n"
+	code += fmt.Sprintf("    x.Extra = make(map[string]%s, len(raw))\n", extraType)
+	code += "    for k, v := range raw {\n"
+	code += fmt.Sprintf("        var value %s\n", extraType)
+	code += "        if err := json.Unmarshal(v, &value); err != nil {\n            return err\n        }\n"
+	code += "        x.Extra[k] = value\n    }\n"
+	code += "    return nil\n}\n\n"
+
+	code += fmt.Sprintf("// MarshalJSON encodes %s, folding Extra back in alongside its modeled properties.\n", typeName)
+	code += fmt.Sprintf("func (x %s) MarshalJSON() ([]byte, error) {\n", typeName)
+	code += fmt.Sprintf("    type alias %s\n", typeName)
+	code += "    known, err := json.Marshal(alias(x))\n    if err != nil {\n        return nil, err\n    }\n"
+	code += "    if len(x.Extra) == 0 {\n        return known, nil\n    }\n"
+	code += "    var merged map[string]json.RawMessage\n"
+	code += "    if err := json.Unmarshal(known, &merged); err != nil {\n        return nil, err\n    }\n"
+	code += "    for k, v := range x.Extra {\n"
+	code += "        raw, err := json.Marshal(v)\n        if err != nil {\n            return nil, err\n        }\n"
+	code += "        merged[k] = raw\n    }\n"
+	code += "    return json.Marshal(merged)\n}\n\n"
+	return code
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// fieldValidation builds the Validate() snippet enforcing the constraints v
+// places on the struct field named fieldName, plus the package-level
+// compiled-regexp declaration for its pattern, if any.
+func (s *SchemaProcessor) fieldValidation(typeName, fieldName string, v *Schema, required bool, fieldType string) (patternDecl string, check string) {
+	goField := camelCase(fieldName)
+	accessor := fmt.Sprintf("x.%s", goField)
+	path := "/" + fieldName
+	var b strings.Builder
+
+	if required {
+		switch v.Type {
+		case STRING:
+			fmt.Fprintf(&b, "    if %s == \"\" {\n        errs.Add(%q, \"is required\")\n    }\n", accessor, path)
+		case ARRAY:
+			fmt.Fprintf(&b, "    if len(%s) == 0 {\n        errs.Add(%q, \"is required\")\n    }\n", accessor, path)
+		}
+	}
+
+	if v.Type == STRING {
+		if v.MinLength != nil {
+			fmt.Fprintf(&b, "    if len(%s) < %d {\n        errs.Add(%q, \"must be at least %d characters\")\n    }\n", accessor, *v.MinLength, path, *v.MinLength)
+		}
+		if v.MaxLength != nil {
+			fmt.Fprintf(&b, "    if len(%s) > %d {\n        errs.Add(%q, \"must be at most %d characters\")\n    }\n", accessor, *v.MaxLength, path, *v.MaxLength)
+		}
+		if v.Pattern != "" {
+			varName := fmt.Sprintf("%s%sPattern", lowerFirst(typeName), goField)
+			patternDecl = fmt.Sprintf("var %s = regexp.MustCompile(%q)\n", varName, v.Pattern)
+			fmt.Fprintf(&b, "    if %s != \"\" && !%s.MatchString(%s) {\n        errs.Add(%q, \"must match pattern\")\n    }\n", accessor, varName, accessor, path)
+		}
+	}
+
+	if v.Type == NUMBER || v.Type == INTEGER {
+		if v.Minimum != nil {
+			fmt.Fprintf(&b, "    if float64(%s) < %g {\n        errs.Add(%q, \"must be >= %g\")\n    }\n", accessor, *v.Minimum, path, *v.Minimum)
+		}
+		if v.Maximum != nil {
+			fmt.Fprintf(&b, "    if float64(%s) > %g {\n        errs.Add(%q, \"must be <= %g\")\n    }\n", accessor, *v.Maximum, path, *v.Maximum)
+		}
+		if v.ExclusiveMinimum != nil {
+			fmt.Fprintf(&b, "    if float64(%s) <= %g {\n        errs.Add(%q, \"must be > %g\")\n    }\n", accessor, *v.ExclusiveMinimum, path, *v.ExclusiveMinimum)
+		}
+		if v.ExclusiveMaximum != nil {
+			fmt.Fprintf(&b, "    if float64(%s) >= %g {\n        errs.Add(%q, \"must be < %g\")\n    }\n", accessor, *v.ExclusiveMaximum, path, *v.ExclusiveMaximum)
+		}
+	}
+
+	if v.Type == ARRAY {
+		if v.MinItems != nil {
+			fmt.Fprintf(&b, "    if len(%s) < %d {\n        errs.Add(%q, \"must have at least %d items\")\n    }\n", accessor, *v.MinItems, path, *v.MinItems)
+		}
+		if v.MaxItems != nil {
+			fmt.Fprintf(&b, "    if len(%s) > %d {\n        errs.Add(%q, \"must have at most %d items\")\n    }\n", accessor, *v.MaxItems, path, *v.MaxItems)
+		}
+		if v.UniqueItems {
+			fmt.Fprintf(&b, "    if seen := make(map[string]bool); true {\n        for _, item := range %s {\n            key := fmt.Sprintf(\"%%v\", item)\n            if seen[key] {\n                errs.Add(%q, \"items must be unique\")\n                break\n            }\n            seen[key] = true\n        }\n    }\n", accessor, path)
+		}
+		if v.Items != nil && v.Items.Type == OBJECT && v.Items.Properties != nil {
+			fmt.Fprintf(&b, "    for i, item := range %s {\n        errs.merge(fmt.Sprintf(\"%%s/%%d\", %q, i), item.Validate())\n    }\n", accessor, path)
+		}
+	}
+
+	if v.Type == OBJECT && v.Properties != nil {
+		if strings.HasPrefix(fieldType, "*") {
+			fmt.Fprintf(&b, "    if %s != nil {\n        errs.merge(%q, %s.Validate())\n    }\n", accessor, path, accessor)
+		} else {
+			fmt.Fprintf(&b, "    errs.merge(%q, %s.Validate())\n", path, accessor)
+		}
+	}
+
+	if v.Type == OBJECT && v.Properties == nil && len(v.PatternProperties) > 0 {
+		keys := make([]string, 0, len(v.PatternProperties))
+		for k := range v.PatternProperties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if mapValue := v.PatternProperties[keys[0]]; mapValue.Type == OBJECT && mapValue.Properties != nil {
+			fmt.Fprintf(&b, "    for k, item := range %s {\n        errs.merge(fmt.Sprintf(\"%%s/%%s\", %q, k), item.Validate())\n    }\n", accessor, path)
+		}
+	}
+
+	// A oneOf/anyOf/allOf property's shape lives in v.OneOf/v.AnyOf/v.AllOf
+	// rather than v.Properties - its v.Type is still ANY - but the generated
+	// union/merged-allOf type still gets its own Validate() that needs calling.
+	// A "{type: null}" + T union simplifies to T itself (see simplifyUnion),
+	// which may not be a struct at all, so that case is excluded here.
+	branches := unionBranches(v)
+	isSimplifiedUnion := branches != nil && simplifyUnion(branches) != nil
+	if (branches != nil && !isSimplifiedUnion) || len(v.AllOf) > 0 {
+		if strings.HasPrefix(fieldType, "*") {
+			fmt.Fprintf(&b, "    if %s != nil {\n        errs.merge(%q, %s.Validate())\n    }\n", accessor, path, accessor)
+		} else {
+			fmt.Fprintf(&b, "    errs.merge(%q, %s.Validate())\n", path, accessor)
+		}
+	}
+
+	return patternDecl, b.String()
+}
+
+// validateMethodCode assembles the Validate() method body for typeName from
+// its accumulated field checks, plus any compiled-pattern vars they need.
+func (s *SchemaProcessor) validateMethodCode(typeName string, patternDecls, checks []string) string {
+	code := ""
+	for _, decl := range patternDecls {
+		code += decl
+	}
+	if len(patternDecls) > 0 {
+		code += "\n"
+	}
+	code += fmt.Sprintf("// Validate enforces the constraints carried over from %s's schema.\n", typeName)
+	code += fmt.Sprintf("func (x *%s) Validate() error {\n", typeName)
+	code += "    errs := &ValidationError{}\n"
+	for _, c := range checks {
+		code += c
+	}
+	code += "    if errs.HasErrors() {\n        return errs\n    }\n    return nil\n}\n\n"
+	return code
+}
+
+// writeGoCode writes out a single generated type. imports lists any stdlib
+// packages the generated code needs (e.g. "time", "encoding/json"); they are
+// deduplicated and rendered as the file's import block.
+func (s *SchemaProcessor) writeGoCode(typeName, code string, imports ...string) error {
 	if seen, ok := s.processed[typeName]; ok && seen {
 		return nil
 	}
@@ -542,38 +1790,12 @@ func (s *SchemaProcessor) writeGoCode(typeName, code string) error {
 		s.processed[typeName] = true
 	}
 
-	if s.Stdout {
-		if s.Fmt {
-			cmd := exec.Command("gofmt", "-s")
-			inPipe, _ := cmd.StdinPipe()
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
-			cmd.Start()
-			inPipe.Write([]byte(code))
-			inPipe.Close()
-			return cmd.Wait()
-		}
-		fmt.Print(code)
-		return nil
-	}
-	file := path.Join(s.OutputDir, fmt.Sprintf("%s.go", typeName))
-	if !s.Overwrite {
-		if _, err := os.Stat(file); err == nil {
-			log.Printf("File %s already exists, skipping without -overwrite", file)
-			return nil
-		}
-	}
-	fh, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-	if err != nil {
-		return err
-	}
-	//defer fh.Close()
 	preamble := fmt.Sprintf("package %s\n", s.PackageName)
 	preamble += fmt.Sprintf(`
 /////////////////////////////////////////////////////////////////////////
 // This Code is Generated by SlipScheme Project:
 // https://github.com/zhongjie-cai/slipscheme
-// 
+//
 // Generated with command:
 // %s
 /////////////////////////////////////////////////////////////////////////
@@ -582,19 +1804,19 @@ func (s *SchemaProcessor) writeGoCode(typeName, code string) error {
 
 `, strings.Join(os.Args, " "))
 
-	if _, err := fh.Write([]byte(preamble)); err != nil {
-		return err
-	}
-	if _, err := fh.Write([]byte(code)); err != nil {
-		return err
-	}
+	full := preamble + importBlock(imports) + code
+	return s.emitter().WriteType(typeName, full)
+}
 
-	if s.Fmt {
-		cmd := exec.Command("gofmt", "-s", "-w", file)
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		return cmd.Run()
+// emitter returns the Emitter generated code is written through, defaulting
+// to a StdoutEmitter or FileEmitter depending on the Stdout flag when none
+// was explicitly configured.
+func (s *SchemaProcessor) emitter() Emitter {
+	if s.Emitter != nil {
+		return s.Emitter
 	}
-	return nil
+	if s.Stdout {
+		return &StdoutEmitter{Fmt: s.Fmt}
+	}
+	return &FileEmitter{Dir: s.OutputDir, Overwrite: s.Overwrite, Fmt: s.Fmt}
 }
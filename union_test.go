@@ -0,0 +1,160 @@
+package slipscheme
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestUnionDispatchMatchesRequiredFields covers the concrete unsoundness the
+// old try-each-in-order UnmarshalJSON had: a payload that only satisfies one
+// branch's required fields must not be decoded into a different branch just
+// because that branch's fields happen to zero-value cleanly.
+func TestUnionDispatchMatchesRequiredFields(t *testing.T) {
+	dir := t.TempDir()
+	specPath := filepath.Join(dir, "payment.json")
+	if err := os.WriteFile(specPath, []byte(`{
+		"title": "Payment",
+		"oneOf": [
+			{
+				"title": "CardPayment",
+				"type": "object",
+				"required": ["cardNumber"],
+				"properties": {"cardNumber": {"type": "string"}}
+			},
+			{
+				"title": "CashPayment",
+				"type": "object",
+				"required": ["amount"],
+				"properties": {"amount": {"type": "number"}}
+			}
+		]
+	}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	emitter := &MemoryEmitter{}
+	processor := NewProcessor(Options{PackageName: "model", Emitter: emitter})
+	if err := processor.Load([]string{specPath}); err != nil {
+		t.Fatal(err)
+	}
+	if err := processor.Process(context.Background()); err != nil {
+		t.Fatalf("Process: %s", err)
+	}
+
+	payment, ok := emitter.Files["Payment"]
+	if !ok {
+		t.Fatalf("expected a Payment type, got %v", mapKeys(emitter.Files))
+	}
+	if !strings.Contains(payment, `hasUnionField(fields, "cardNumber")`) {
+		t.Fatalf("expected dispatch on CardPayment's required field, got: %s", payment)
+	}
+	if !strings.Contains(payment, `hasUnionField(fields, "amount")`) {
+		t.Fatalf("expected dispatch on CashPayment's required field, got: %s", payment)
+	}
+	if strings.Contains(payment, "tries each alternative") {
+		t.Fatalf("expected the unsound try-each-and-keep-first UnmarshalJSON to be gone, got: %s", payment)
+	}
+}
+
+// TestUnionDiscriminatorDispatch covers dispatching via an OpenAPI
+// discriminator instead of required-field matching, when one is given.
+func TestUnionDiscriminatorDispatch(t *testing.T) {
+	dir := t.TempDir()
+	specPath := filepath.Join(dir, "pet.json")
+	if err := os.WriteFile(specPath, []byte(`{
+		"title": "Pet",
+		"discriminator": {"propertyName": "petType"},
+		"oneOf": [
+			{"title": "Cat", "type": "object", "properties": {"petType": {"type": "string"}}},
+			{"title": "Dog", "type": "object", "properties": {"petType": {"type": "string"}}}
+		]
+	}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	emitter := &MemoryEmitter{}
+	processor := NewProcessor(Options{PackageName: "model", Emitter: emitter})
+	if err := processor.Load([]string{specPath}); err != nil {
+		t.Fatal(err)
+	}
+	if err := processor.Process(context.Background()); err != nil {
+		t.Fatalf("Process: %s", err)
+	}
+
+	pet, ok := emitter.Files["Pet"]
+	if !ok {
+		t.Fatalf("expected a Pet type, got %v", mapKeys(emitter.Files))
+	}
+	if !strings.Contains(pet, `json:"petType"`) || !strings.Contains(pet, `case "Cat":`) {
+		t.Fatalf("expected discriminator-based dispatch, got: %s", pet)
+	}
+}
+
+// TestAllOfMerge covers composing allOf's branches into a single object
+// schema instead of silently dropping the composition to interface{}.
+func TestAllOfMerge(t *testing.T) {
+	dir := t.TempDir()
+	specPath := filepath.Join(dir, "employee.json")
+	if err := os.WriteFile(specPath, []byte(`{
+		"title": "Employee",
+		"allOf": [
+			{"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}},
+			{"type": "object", "properties": {"salary": {"type": "number"}}}
+		]
+	}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	emitter := &MemoryEmitter{}
+	processor := NewProcessor(Options{PackageName: "model", Emitter: emitter})
+	if err := processor.Load([]string{specPath}); err != nil {
+		t.Fatal(err)
+	}
+	if err := processor.Process(context.Background()); err != nil {
+		t.Fatalf("Process: %s", err)
+	}
+
+	employee, ok := emitter.Files["Employee"]
+	if !ok {
+		t.Fatalf("expected an Employee type merging both allOf branches, got %v", mapKeys(emitter.Files))
+	}
+	if !strings.Contains(employee, "Name") || !strings.Contains(employee, "Salary") {
+		t.Fatalf("expected both branches' fields merged, got: %s", employee)
+	}
+}
+
+// TestConstProducesValidatingType covers const being read at all: it used to
+// be parsed and then ignored, falling through to interface{}.
+func TestConstProducesValidatingType(t *testing.T) {
+	dir := t.TempDir()
+	specPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(specPath, []byte(`{
+		"title": "Config",
+		"type": "object",
+		"properties": {
+			"version": {"const": "v1"}
+		}
+	}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	emitter := &MemoryEmitter{}
+	processor := NewProcessor(Options{PackageName: "model", Emitter: emitter})
+	if err := processor.Load([]string{specPath}); err != nil {
+		t.Fatal(err)
+	}
+	if err := processor.Process(context.Background()); err != nil {
+		t.Fatalf("Process: %s", err)
+	}
+
+	config, ok := emitter.Files["Config"]
+	if !ok {
+		t.Fatalf("expected a Config type, got %v", mapKeys(emitter.Files))
+	}
+	if strings.Contains(config, "interface{}") {
+		t.Fatalf("expected version's const to produce a named type, not interface{}: %s", config)
+	}
+}
@@ -0,0 +1,52 @@
+package slipscheme
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestArrayValidationRecursesIntoElements covers Validate() walking into
+// array elements that have their own constraints, instead of only checking
+// minItems/maxItems/uniqueItems on the array field itself.
+func TestArrayValidationRecursesIntoElements(t *testing.T) {
+	dir := t.TempDir()
+	specPath := filepath.Join(dir, "order.json")
+	if err := os.WriteFile(specPath, []byte(`{
+		"title": "Order",
+		"type": "object",
+		"properties": {
+			"items": {
+				"title": "Items",
+				"type": "array",
+				"items": {
+					"title": "LineItem",
+					"type": "object",
+					"required": ["sku"],
+					"properties": {"sku": {"type": "string"}}
+				}
+			}
+		}
+	}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	emitter := &MemoryEmitter{}
+	processor := NewProcessor(Options{PackageName: "model", Emitter: emitter, Validate: true})
+	if err := processor.Load([]string{specPath}); err != nil {
+		t.Fatal(err)
+	}
+	if err := processor.Process(context.Background()); err != nil {
+		t.Fatalf("Process: %s", err)
+	}
+
+	order, ok := emitter.Files["Order"]
+	if !ok {
+		t.Fatalf("expected an Order type, got %v", mapKeys(emitter.Files))
+	}
+	if !strings.Contains(order, "for i, item := range x.Items") || !strings.Contains(order, "item.Validate()") {
+		t.Fatalf("expected Order.Validate() to recurse into each item, got: %s", order)
+	}
+}